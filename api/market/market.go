@@ -2,18 +2,52 @@ package market
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/asiainfoLDP/datafoundry_payment/api"
+	"github.com/asiainfoLDP/datafoundry_payment/pkg"
 	"github.com/julienschmidt/httprouter"
 	"github.com/zonesan/clog"
 )
 
+// marketListResponse carries the plan catalog together with the pagination
+// info parsed from the upstream Link header, so callers (the DataFoundry UI)
+// can discover whether there's a next page without inspecting HTTP headers.
+type marketListResponse struct {
+	Plans     []*pkg.Plan `json:"plans"`
+	NextPage  int         `json:"next_page,omitempty"`
+	PrevPage  int         `json:"prev_page,omitempty"`
+	FirstPage int         `json:"first_page,omitempty"`
+	LastPage  int         `json:"last_page,omitempty"`
+}
+
 func Market(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	clog.Info("from", r.RemoteAddr, r.Method, r.URL.RequestURI(), r.Proto)
 
 	agent := api.Agent()
-	market := agent.Market.Get()
 
-	api.RespOK(w, market)
+	var opts pkg.ListOptions
+	if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil {
+		opts.Page = page
+	}
+	if perPage, err := strconv.Atoi(r.URL.Query().Get("per_page")); err == nil {
+		opts.PerPage = perPage
+	}
+
+	market, resp, err := agent.Market.List(r.Context(), &opts)
+	if err != nil {
+		api.RespErr(w, err)
+		return
+	}
+
+	out := marketListResponse{Plans: market}
+	if resp != nil {
+		out.NextPage = resp.NextPage
+		out.PrevPage = resp.PrevPage
+		out.FirstPage = resp.FirstPage
+		out.LastPage = resp.LastPage
+	}
+
+	api.RespOK(w, out)
 
-}
\ No newline at end of file
+}
@@ -0,0 +1,25 @@
+package pkg
+
+import "context"
+
+// AccountAgent handles communication with the account endpoints of the
+// payment components API.
+type AccountAgent service
+
+// Account represents a payment account.
+type Account struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Get retrieves the authenticated account.
+func (a *AccountAgent) Get(ctx context.Context) (*Account, error) {
+	req, err := a.agent.NewRequestWithContext(ctx, "GET", "account", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var acc Account
+	_, err = a.agent.Do(ctx, req, &acc)
+	return &acc, err
+}
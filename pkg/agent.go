@@ -2,31 +2,128 @@ package pkg
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
 )
 
 const (
-	defaultBaseURL = "http://localhost:7071"
+	defaultBaseURL   = "http://localhost:7071"
+	defaultUserAgent = "datafoundry_payment-agent"
+
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
 )
 
+// Logger is the minimal logging interface accepted by WithLogger.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// RetryPolicy controls how Agent.Do retries failed requests.
+type RetryPolicy struct {
+	MaxRetries    int           // maximum number of retries, 0 disables retrying
+	MinRetryDelay time.Duration // initial backoff delay
+	MaxRetryDelay time.Duration // upper bound for backoff delay
+}
+
+// DefaultRetryPolicy is used by NewAgent when no RetryPolicy is configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:    3,
+	MinRetryDelay: 200 * time.Millisecond,
+	MaxRetryDelay: 5 * time.Second,
+}
+
+// backoff returns the delay before the n-th retry (n starting at 0), with
+// full jitter applied on top of a capped exponential backoff.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.MinRetryDelay << uint(n)
+	if d <= 0 || d > p.MaxRetryDelay {
+		d = p.MaxRetryDelay
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// shouldRetry reports whether a response to the given method warrants a
+// retry under the policy. Retries are only attempted for idempotent
+// methods: retrying a non-idempotent POST (e.g. Checkout, Recharge) on a
+// 5xx risks re-submitting a charge whose response was merely lost, so
+// those are never retried automatically.
+func (p RetryPolicy) shouldRetry(method string, statusCode int) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return statusCode >= 500 && statusCode <= 599
+}
+
+// Rate represents the rate limit for the current client as reported by the
+// payment components API in the X-RateLimit-* response headers.
+type Rate struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("limit=%d remaining=%d reset=%v", r.Limit, r.Remaining, r.Reset)
+}
+
+func parseRate(resp *http.Response) Rate {
+	var rate Rate
+	if limit := resp.Header.Get(headerRateLimit); limit != "" {
+		rate.Limit, _ = strconv.Atoi(limit)
+	}
+	if remaining := resp.Header.Get(headerRateRemaining); remaining != "" {
+		rate.Remaining, _ = strconv.Atoi(remaining)
+	}
+	if reset := resp.Header.Get(headerRateReset); reset != "" {
+		if v, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			rate.Reset = time.Unix(v, 0)
+		}
+	}
+	return rate
+}
+
 // An Agent manages communication with the payment components API.
 type Agent struct {
 	clientMu sync.Mutex   // clientMu protects the client during calls that modify the CheckRedirect func.
 	client   *http.Client // HTTP client used to communicate with the API.
 
-	// Base URL for API requests.  Defaults to the public GitHub API, BaseURL should
-	// always be specified with a trailing slash.
+	// Base URL for API requests.  BaseURL should always be specified with a
+	// trailing slash.
+	BaseURL *url.URL
 
-	// BaseURL *url.URL
+	// User agent used when communicating with the payment components API.
+	UserAgent string
 
 	common service // Reuse a single struct instead of allocating one for each service on the heap.
 
+	limiter     *rate.Limiter // client-side token-bucket limiter throttling outbound requests.
+	retryPolicy RetryPolicy   // retry behavior for 429/5xx responses.
+
+	rateMu sync.Mutex // rateMu protects rate.
+	rate   Rate       // rate is the last rate limit reported by the API.
+
+	transportsMu sync.RWMutex // transportsMu protects transports.
+	transports   []Transport  // middleware chain run around every round trip, see Use.
+
 	// Agents used for talking to different parts of the payment components API.
 	Recharge *RechargeAgent
 	Checkout *CheckoutAgent
@@ -41,16 +138,102 @@ type service struct {
 	agent *Agent
 }
 
-func NewAgent(httpClient *http.Client) *Agent {
+// Option configures an Agent. Options are applied in the order given to
+// NewAgent, after the built-in defaults have been set.
+type Option func(*Agent)
+
+// WithBaseURL overrides the default base URL used to resolve relative
+// request paths, e.g. when pointing the agent at a staging environment.
+// baseURL is normalized to end with a trailing slash, since BaseURL.
+// ResolveReference otherwise drops the last path segment per RFC 3986
+// merge rules (e.g. "/api" in "https://host/api" would be silently lost).
+func WithBaseURL(baseURL string) Option {
+	return func(a *Agent) {
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return
+		}
+		a.BaseURL = u
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(a *Agent) {
+		a.UserAgent = userAgent
+	}
+}
+
+// WithRateLimit configures the client-side token-bucket limiter. r is the
+// steady-state number of requests per second allowed, burst is the maximum
+// number of requests that may be issued in a single burst.
+func WithRateLimit(r rate.Limit, burst int) Option {
+	return func(a *Agent) {
+		a.limiter = rate.NewLimiter(r, burst)
+	}
+}
+
+// WithRetryPolicy overrides the default retry behavior for 429/5xx
+// responses.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(a *Agent) {
+		a.retryPolicy = policy
+	}
+}
+
+// WithLogger installs logger so that every outbound request and its
+// resulting status code are logged, via the generic middleware chain (Use).
+func WithLogger(logger Logger) Option {
+	return func(a *Agent) {
+		a.Use(LoggingTransport(logger))
+	}
+}
+
+// WithClogLogging installs a Transport that logs every outbound request
+// through the zonesan/clog structured logger shared by the rest of
+// DataFoundry's HTTP handlers.
+func WithClogLogging() Option {
+	return func(a *Agent) {
+		a.Use(ClogTransport())
+	}
+}
+
+// WithMetrics installs a Transport that records request latency and in-flight
+// request count via Prometheus. ErrCode*-labelled errors are counted by Do
+// itself, whether or not this option is set.
+func WithMetrics() Option {
+	return func(a *Agent) {
+		a.Use(MetricsTransport())
+	}
+}
+
+// WithTracing installs a Transport that injects the active OpenTelemetry
+// span context into outbound request headers, so payment flows can be
+// correlated end-to-end across DataFoundry services.
+func WithTracing() Option {
+	return func(a *Agent) {
+		a.Use(TraceTransport())
+	}
+}
+
+func NewAgent(httpClient *http.Client, opts ...Option) *Agent {
 
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
 
-	// baseURL, _ := url.Parse(defaultBaseURL)
+	baseURL, _ := url.Parse(defaultBaseURL)
 
-	// agent := &Agent{client: httpClient, BaseURL: baseURL}
-	agent := &Agent{client: httpClient}
+	agent := &Agent{
+		client:      httpClient,
+		BaseURL:     baseURL,
+		UserAgent:   defaultUserAgent,
+		limiter:     rate.NewLimiter(rate.Inf, 0),
+		retryPolicy: DefaultRetryPolicy,
+	}
 
 	agent.common.agent = agent
 	agent.Account = (*AccountAgent)(&agent.common)
@@ -61,6 +244,10 @@ func NewAgent(httpClient *http.Client) *Agent {
 	agent.Market = (*MarketAgent)(&agent.common)
 	agent.Recharge = (*RechargeAgent)(&agent.common)
 
+	for _, opt := range opts {
+		opt(agent)
+	}
+
 	return agent
 }
 
@@ -69,12 +256,28 @@ func NewAgent(httpClient *http.Client) *Agent {
 // Relative URLs should always be specified without a preceding slash.  If
 // specified, the value pointed to by body is JSON encoded and included as the
 // request body.
-func (*Agent) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+//
+// NewRequest has no way to carry a context; callers should prefer
+// NewRequestWithContext.
+func (a *Agent) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	return a.NewRequestWithContext(context.Background(), method, urlStr, body)
+}
+
+// NewRequestWithContext creates an API request like NewRequest, but the
+// returned request carries ctx so that Do can cancel or time out the
+// outbound call. A relative urlStr is resolved against a.BaseURL.
+func (a *Agent) NewRequestWithContext(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
 
-	if _, err := url.Parse(urlStr); err != nil {
+	rel, err := url.Parse(urlStr)
+	if err != nil {
 		return nil, err
 	}
 
+	u := rel
+	if a.BaseURL != nil {
+		u = a.BaseURL.ResolveReference(rel)
+	}
+
 	var buf io.ReadWriter
 	if body != nil {
 		buf = new(bytes.Buffer)
@@ -84,18 +287,18 @@ func (*Agent) NewRequest(method, urlStr string, body interface{}) (*http.Request
 		}
 	}
 
-	req, err := http.NewRequest(method, urlStr, buf)
+	req, err := http.NewRequest(method, u.String(), buf)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
-	// req.Header.Set("Accept", mediaTypeV3)
-	// if c.UserAgent != "" {
-	// 	req.Header.Set("User-Agent", c.UserAgent)
-	// }
+	if a.UserAgent != "" {
+		req.Header.Set("User-Agent", a.UserAgent)
+	}
 	return req, nil
 }
 
@@ -105,81 +308,131 @@ func (*Agent) NewRequest(method, urlStr string, body interface{}) (*http.Request
 // interface, the raw response body will be written to v, without attempting to
 // first decode it.  If rate limit is exceeded and reset time is in the future,
 // Do returns *RateLimitError immediately without making a network API call.
-func (c *Agent) Do(req *http.Request, v interface{}) error {
+// The returned *Response wraps the underlying http.Response and, for List*
+// calls, surfaces pagination info parsed from the Link header.
+//
+// The provided ctx must be non-nil. If it is canceled or times out, Do
+// returns the ctx's error and aborts the in-flight request, any pending
+// retry wait, and the rate limiter wait.
+func (c *Agent) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, error) {
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return err
+	req = req.WithContext(ctx)
+
+	if rl := c.checkRateLimitBeforeDo(); rl != nil {
+		// This short-circuit never reaches roundTrip, so it bypasses
+		// MetricsTransport/ClogTransport/TraceTransport entirely. Record it
+		// against the same error counter the retry loop below feeds, so a
+		// throttling incident doesn't go invisible in Prometheus right when
+		// observability matters most.
+		recordAPIError(rl)
+		return nil, rl
 	}
 
-	defer func() {
+	var httpResp *http.Response
+	var cerr error
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			// http.Transport drains and closes the previous attempt's body;
+			// rebuild it from the snapshot NewRequest stashed away so the
+			// retried request carries the same payload as the original.
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		var err error
+		httpResp, err = c.roundTrip(req)
+		if err != nil {
+			// If the context was canceled or timed out, return its error
+			// rather than the http.Client's wrapped one.
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			default:
+			}
+			return nil, err
+		}
+
+		c.updateRate(httpResp)
+
+		cerr = CheckResponse(httpResp)
+		if cerr == nil {
+			break
+		}
+
 		// Drain up to 512 bytes and close the body to let the Transport reuse the connection
-		io.CopyN(ioutil.Discard, resp.Body, 512)
-		resp.Body.Close()
-	}()
+		io.CopyN(ioutil.Discard, httpResp.Body, 512)
+		httpResp.Body.Close()
 
-	err = CheckResponse(resp)
-	if err != nil {
-		// even though there was an error, we still return the response
-		// in case the caller wants to inspect it further
-		return err
+		if attempt >= c.retryPolicy.MaxRetries || !c.retryPolicy.shouldRetry(req.Method, httpResp.StatusCode) {
+			recordAPIError(cerr)
+			return newResponse(httpResp), cerr
+		}
+
+		select {
+		case <-time.After(c.retryPolicy.backoff(attempt)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
 	}
 
+	resp := newResponse(httpResp)
+
+	defer func() {
+		// Drain up to 512 bytes and close the body to let the Transport reuse the connection
+		io.CopyN(ioutil.Discard, httpResp.Body, 512)
+		httpResp.Body.Close()
+	}()
+
 	if v != nil {
 		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
+			io.Copy(w, httpResp.Body)
 		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
+			err := json.NewDecoder(httpResp.Body).Decode(v)
 			if err == io.EOF {
 				err = nil // ignore EOF errors caused by empty response body
 			}
+			return resp, err
 		}
 	}
 
-	return err
-}
-
-type Error struct {
-	Resource string `json:"resource"` // resource on which the error occurred
-	Field    string `json:"field"`    // field on which the error occurred
-	Code     string `json:"code"`     // validation error code
-	Message  string `json:"message"`  // Message describing the error. Errors with Code == "custom" will always have this set.
+	return resp, nil
 }
 
-func (e *Error) Error() string {
-	return fmt.Sprintf("%v error caused by %v field on %v resource",
-		e.Code, e.Field, e.Resource)
+// Rate returns the last rate limit observed from the payment components API.
+func (c *Agent) Rate() Rate {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	return c.rate
 }
 
-type ErrorResponse struct {
-	Response *http.Response // HTTP response that caused this error
-	Message  string         `json:"message"` // error message
-	Errors   []Error        `json:"errors"`  // more detail on individual errors
-
+func (c *Agent) updateRate(resp *http.Response) {
+	r := parseRate(resp)
+	if r.Limit == 0 && r.Remaining == 0 && r.Reset.IsZero() {
+		return
+	}
+	c.rateMu.Lock()
+	c.rate = r
+	c.rateMu.Unlock()
 }
 
-func (r *ErrorResponse) Error() string {
-	return fmt.Sprintf("%v %v: %d %v %+v",
-		r.Response.Request.Method, r.Response.Request.URL,
-		r.Response.StatusCode, r.Message, r.Errors)
-}
+// checkRateLimitBeforeDo returns a *RateLimitError without making a network
+// call if the previously observed rate limit is exhausted and its reset
+// time has not yet passed.
+func (c *Agent) checkRateLimitBeforeDo() *RateLimitError {
+	c.rateMu.Lock()
+	observed := c.rate
+	c.rateMu.Unlock()
 
-// CheckResponse checks the API response for errors, and returns them if
-// present.  A response is considered an error if it has a status code outside
-// the 200 range.  API error responses are expected to have either no response
-// body, or a JSON response body that maps to ErrorResponse.  Any other
-// response body will be silently ignored.
-//
-// The error type will be *RateLimitError for rate limit exceeded errors,
-// and *TwoFactorAuthError for two-factor authentication errors.
-func CheckResponse(r *http.Response) error {
-	if c := r.StatusCode; 200 <= c && c <= 299 {
+	if observed.Remaining > 0 || observed.Reset.IsZero() || time.Now().After(observed.Reset) {
 		return nil
 	}
-	errorResponse := &ErrorResponse{Response: r}
-	data, err := ioutil.ReadAll(r.Body)
-	if err == nil && data != nil {
-		json.Unmarshal(data, errorResponse)
-	}
-	return errorResponse
-}
\ No newline at end of file
+	return &RateLimitError{APIError: &APIError{Code: ErrCodeRateLimited, Message: ErrText(ErrCodeRateLimited)}, Rate: observed}
+}
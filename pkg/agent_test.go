@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MinRetryDelay: 100 * time.Millisecond, MaxRetryDelay: time.Second}
+
+	for n := 0; n < 6; n++ {
+		for i := 0; i < 20; i++ {
+			d := policy.backoff(n)
+			if d < 0 || d > policy.MaxRetryDelay {
+				t.Fatalf("backoff(%d) = %v, want in [0, %v]", n, d, policy.MaxRetryDelay)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyBackoffZeroMinDelay(t *testing.T) {
+	policy := RetryPolicy{MinRetryDelay: 0, MaxRetryDelay: time.Second}
+	if d := policy.backoff(0); d < 0 || d > policy.MaxRetryDelay {
+		t.Fatalf("backoff(0) = %v, want in [0, %v]", d, policy.MaxRetryDelay)
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	policy := DefaultRetryPolicy
+
+	tests := []struct {
+		method     string
+		statusCode int
+		want       bool
+	}{
+		{http.MethodGet, http.StatusTooManyRequests, true},
+		{http.MethodGet, http.StatusInternalServerError, true},
+		{http.MethodGet, http.StatusBadGateway, true},
+		{http.MethodGet, http.StatusOK, false},
+		{http.MethodGet, http.StatusNotFound, false},
+		{http.MethodHead, http.StatusServiceUnavailable, true},
+		{http.MethodPut, http.StatusServiceUnavailable, true},
+		{http.MethodDelete, http.StatusServiceUnavailable, true},
+		{http.MethodOptions, http.StatusServiceUnavailable, true},
+		// POST/PATCH are not idempotent: a 5xx might mean the charge went
+		// through but the response was lost, so they must never be retried
+		// automatically.
+		{http.MethodPost, http.StatusTooManyRequests, false},
+		{http.MethodPost, http.StatusInternalServerError, false},
+		{http.MethodPatch, http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		if got := policy.shouldRetry(tt.method, tt.statusCode); got != tt.want {
+			t.Errorf("shouldRetry(%q, %d) = %v, want %v", tt.method, tt.statusCode, got, tt.want)
+		}
+	}
+}
+
+func TestAgentCheckRateLimitBeforeDo(t *testing.T) {
+	tests := []struct {
+		name string
+		rate Rate
+		want bool
+	}{
+		{"no rate observed yet", Rate{}, false},
+		{"remaining quota left", Rate{Remaining: 1, Reset: time.Now().Add(time.Hour)}, false},
+		{"reset already passed", Rate{Remaining: 0, Reset: time.Now().Add(-time.Minute)}, false},
+		{"exhausted and not yet reset", Rate{Remaining: 0, Reset: time.Now().Add(time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		a := &Agent{rate: tt.rate}
+		rl := a.checkRateLimitBeforeDo()
+		if got := rl != nil; got != tt.want {
+			t.Errorf("%s: checkRateLimitBeforeDo() returned non-nil = %v, want %v", tt.name, got, tt.want)
+		}
+		if rl != nil && rl.Code != ErrCodeRateLimited {
+			t.Errorf("%s: Code = %d, want %d", tt.name, rl.Code, ErrCodeRateLimited)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package pkg
+
+import "context"
+
+// AmountAgent handles communication with the pricing/amount lookup
+// endpoints of the payment components API.
+type AmountAgent service
+
+// Amount represents the priced quantity for a plan.
+type Amount struct {
+	PlanID string  `json:"plan_id"`
+	Value  float64 `json:"value"`
+}
+
+// Get retrieves the amount for the given plan.
+func (a *AmountAgent) Get(ctx context.Context, planID string) (*Amount, error) {
+	req, err := a.agent.NewRequestWithContext(ctx, "GET", "amount/"+planID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var am Amount
+	_, err = a.agent.Do(ctx, req, &am)
+	return &am, err
+}
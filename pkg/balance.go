@@ -0,0 +1,25 @@
+package pkg
+
+import "context"
+
+// BalanceAgent handles communication with the account balance endpoint of
+// the payment components API.
+type BalanceAgent service
+
+// Balance represents an account's current balance.
+type Balance struct {
+	AccountID string  `json:"account_id"`
+	Amount    float64 `json:"amount"`
+}
+
+// Get retrieves the balance for the authenticated account.
+func (a *BalanceAgent) Get(ctx context.Context) (*Balance, error) {
+	req, err := a.agent.NewRequestWithContext(ctx, "GET", "balance", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var b Balance
+	_, err = a.agent.Do(ctx, req, &b)
+	return &b, err
+}
@@ -0,0 +1,31 @@
+package pkg
+
+import "context"
+
+// CheckoutAgent handles communication with the checkout endpoints of the
+// payment components API.
+type CheckoutAgent service
+
+// CheckoutRequest is the payload for starting a checkout.
+type CheckoutRequest struct {
+	PlanID    string `json:"plan_id"`
+	AccountID string `json:"account_id"`
+}
+
+// Checkout represents the result of a checkout call.
+type Checkout struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// Create starts a new checkout.
+func (a *CheckoutAgent) Create(ctx context.Context, in *CheckoutRequest) (*Checkout, error) {
+	req, err := a.agent.NewRequestWithContext(ctx, "POST", "checkout", in)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Checkout
+	_, err = a.agent.Do(ctx, req, &c)
+	return &c, err
+}
@@ -0,0 +1,43 @@
+package pkg
+
+import "context"
+
+// CouponAgent handles communication with the coupon endpoints of the
+// payment components API.
+type CouponAgent service
+
+// Coupon represents a redeemable discount coupon.
+type Coupon struct {
+	Code   string  `json:"code"`
+	Amount float64 `json:"amount"`
+}
+
+// Get retrieves a single coupon by code.
+func (a *CouponAgent) Get(ctx context.Context, code string) (*Coupon, error) {
+	req, err := a.agent.NewRequestWithContext(ctx, "GET", "coupon/"+code, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Coupon
+	_, err = a.agent.Do(ctx, req, &c)
+	return &c, err
+}
+
+// List retrieves the coupons available to the account, honoring pagination
+// via opts.
+func (a *CouponAgent) List(ctx context.Context, opts *ListOptions) ([]*Coupon, *Response, error) {
+	u, err := addOptions("coupon", opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := a.agent.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var list []*Coupon
+	resp, err := a.agent.Do(ctx, req, &list)
+	return list, resp, err
+}
@@ -1,9 +1,15 @@
 package pkg
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
 )
 
+const headerRequestID = "X-Request-ID"
+
 const (
 	ErrCodeOK                 = 1200
 	ErrCodeBadRequest         = 1400
@@ -17,6 +23,7 @@ const (
 	ErrCodeRegionNotFound     = 14041
 	ErrCodeMethodNotAllowed   = 1405
 	ErrCodeTimeout            = 1408
+	ErrCodeRateLimited        = 1429
 	ErrCodeAdminNotPresented  = 15000
 	ErrCodeServiceUnavailable = 1503
 
@@ -36,6 +43,7 @@ var errText = map[int]string{
 	ErrCodeRegionNotFound:     "Region not exist",
 	ErrCodeMethodNotAllowed:   "Method not allowed",
 	ErrCodeTimeout:            "Request timeout",
+	ErrCodeRateLimited:        "Rate limit exceeded",
 	ErrCodeAdminNotPresented:  "Admin not presented",
 	ErrCodeServiceUnavailable: "Service unavailable",
 
@@ -66,3 +74,140 @@ func ErrorNew(code int) error {
 	var e ErrorMessage
 	return e.New(code)
 }
+
+// Error represents a single field-level validation error, as returned in
+// the Errors slice of an APIError.
+type Error struct {
+	Resource string `json:"resource"` // resource on which the error occurred
+	Field    string `json:"field"`    // field on which the error occurred
+	Code     string `json:"code"`     // validation error code
+	Message  string `json:"message"`  // Message describing the error. Errors with Code == "custom" will always have this set.
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v error caused by %v field on %v resource",
+		e.Code, e.Field, e.Resource)
+}
+
+// APIError is the structured error returned by the payment components API.
+// It carries the numeric ErrCode* code, the originating HTTP response, a
+// human-readable message, any field-level Errors, and the X-Request-ID
+// correlating this call across DataFoundry services.
+type APIError struct {
+	Response  *http.Response `json:"-"` // HTTP response that caused this error
+	Code      int            `json:"code"`
+	Message   string         `json:"message"`
+	Errors    []Error        `json:"errors,omitempty"`
+	RequestID string         `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.Response != nil {
+		return fmt.Sprintf("%v %v: %d [code=%d request_id=%v] %v %+v",
+			e.Response.Request.Method, e.Response.Request.URL,
+			e.Response.StatusCode, e.Code, e.RequestID, e.Message, e.Errors)
+	}
+	return fmt.Sprintf("[code=%d request_id=%v] %v", e.Code, e.RequestID, e.Message)
+}
+
+// Is lets errors.Is(err, ErrorNew(pkg.ErrCodeNotFound)) match an APIError (or
+// any of the typed variants below, which embed it) carrying the same
+// ErrCode* code, independent of HTTP status or message text.
+func (e *APIError) Is(target error) bool {
+	switch t := target.(type) {
+	case *ErrorMessage:
+		return e.Code == t.Code
+	case *APIError:
+		return e.Code == t.Code
+	}
+	return false
+}
+
+func newAPIError(r *http.Response) *APIError {
+	e := &APIError{
+		Response:  r,
+		Code:      ErrCodeUnknownError,
+		Message:   ErrText(ErrCodeUnknownError),
+		RequestID: r.Header.Get(headerRequestID),
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil && len(data) > 0 {
+		json.Unmarshal(data, e)
+	}
+	return e
+}
+
+// NotFoundError is returned for ErrCodeNotFound, ErrCodePlanNotFound, and
+// ErrCodeRegionNotFound responses.
+type NotFoundError struct{ *APIError }
+
+// Unwrap allows errors.Is/errors.As to reach the underlying APIError.
+func (e *NotFoundError) Unwrap() error { return e.APIError }
+
+// ForbiddenError is returned for ErrCodeForbidden responses.
+type ForbiddenError struct{ *APIError }
+
+// Unwrap allows errors.Is/errors.As to reach the underlying APIError.
+func (e *ForbiddenError) Unwrap() error { return e.APIError }
+
+// PermissionDeniedError is returned when an operation requires admin
+// authorization that was not presented (ErrCodePermissionDenied,
+// ErrCodeAdminNotPresented), analogous to a two-factor challenge in other
+// APIs.
+type PermissionDeniedError struct{ *APIError }
+
+// Unwrap allows errors.Is/errors.As to reach the underlying APIError.
+func (e *PermissionDeniedError) Unwrap() error { return e.APIError }
+
+// RateLimitError occurs when the payment components API returns 429 and the
+// reset window reported by the X-RateLimit-* headers has not yet passed.
+type RateLimitError struct {
+	*APIError
+	Rate Rate `json:"rate"`
+}
+
+// Unwrap allows errors.Is/errors.As to reach the underlying APIError.
+func (r *RateLimitError) Unwrap() error { return r.APIError }
+
+func (r *RateLimitError) Error() string {
+	if r.APIError.Response == nil {
+		return fmt.Sprintf("rate limit exceeded, not retrying until %v", r.Rate.Reset)
+	}
+	return fmt.Sprintf("%v: %v", r.APIError.Error(), r.Rate)
+}
+
+// CheckResponse checks the API response for errors, and returns them if
+// present. A response is considered an error if it has a status code outside
+// the 200 range. Error response bodies are expected to be JSON decodable
+// into APIError; any body that fails to decode still yields an APIError
+// carrying the default ErrCodeUnknownError message.
+//
+// The concrete error type is *RateLimitError for rate limit exceeded
+// responses, *NotFoundError, *ForbiddenError, or *PermissionDeniedError for
+// the matching ErrCode*, and *APIError otherwise. Use errors.As to recover
+// the concrete type, or errors.Is against an ErrorNew(ErrCode*) sentinel to
+// match on code alone.
+func CheckResponse(r *http.Response) error {
+	if c := r.StatusCode; 200 <= c && c <= 299 {
+		return nil
+	}
+
+	apiErr := newAPIError(r)
+
+	if r.StatusCode == http.StatusTooManyRequests {
+		rate := parseRate(r)
+		if !rate.Reset.IsZero() && time.Now().Before(rate.Reset) {
+			return &RateLimitError{APIError: apiErr, Rate: rate}
+		}
+	}
+
+	switch apiErr.Code {
+	case ErrCodeNotFound, ErrCodePlanNotFound, ErrCodeRegionNotFound:
+		return &NotFoundError{apiErr}
+	case ErrCodeForbidden:
+		return &ForbiddenError{apiErr}
+	case ErrCodePermissionDenied, ErrCodeAdminNotPresented:
+		return &PermissionDeniedError{apiErr}
+	}
+	return apiErr
+}
@@ -0,0 +1,43 @@
+package pkg
+
+import "context"
+
+// MarketAgent handles communication with the market/plan catalog methods of
+// the payment components API.
+type MarketAgent service
+
+// Plan describes a single pricing plan in the market catalog.
+type Plan struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// Get retrieves the full market/plan catalog, with no pagination.
+func (a *MarketAgent) Get(ctx context.Context) ([]*Plan, error) {
+	req, err := a.agent.NewRequestWithContext(ctx, "GET", "market", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var plans []*Plan
+	_, err = a.agent.Do(ctx, req, &plans)
+	return plans, err
+}
+
+// List retrieves the market/plan catalog, honoring pagination via opts.
+func (a *MarketAgent) List(ctx context.Context, opts *ListOptions) ([]*Plan, *Response, error) {
+	u, err := addOptions("market", opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := a.agent.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var plans []*Plan
+	resp, err := a.agent.Do(ctx, req, &plans)
+	return plans, resp, err
+}
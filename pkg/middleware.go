@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zonesan/clog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Handler performs the actual round trip of a request, the terminal step of
+// a middleware chain.
+type Handler func(*http.Request) (*http.Response, error)
+
+// Transport is one link in an Agent's middleware chain. It may inspect or
+// modify req before calling next, and inspect the resulting response/error
+// before returning it. Transports are composed with Use, in the order
+// given: the first Transport passed to Use sees the request first.
+type Transport func(req *http.Request, next Handler) (*http.Response, error)
+
+// Use appends one or more Transports to the Agent's middleware chain, so
+// operators can add cross-cutting behavior such as logging, metrics, or
+// trace propagation without editing each sub-agent. Use returns the Agent
+// so it can be chained from a NewAgent option. Use is safe to call
+// concurrently with in-flight Do calls.
+func (c *Agent) Use(t ...Transport) *Agent {
+	c.transportsMu.Lock()
+	c.transports = append(c.transports, t...)
+	c.transportsMu.Unlock()
+	return c
+}
+
+// roundTrip executes req through the configured middleware chain, ending
+// with the underlying http.Client.
+func (c *Agent) roundTrip(req *http.Request) (*http.Response, error) {
+	c.transportsMu.RLock()
+	transports := make([]Transport, len(c.transports))
+	copy(transports, c.transports)
+	c.transportsMu.RUnlock()
+
+	h := Handler(c.client.Do)
+	for i := len(transports) - 1; i >= 0; i-- {
+		t, next := transports[i], h
+		h = func(r *http.Request) (*http.Response, error) {
+			return t(r, next)
+		}
+	}
+	return h(req)
+}
+
+// LoggingTransport logs every outbound request and its outcome through the
+// generic Logger interface installed via WithLogger.
+func LoggingTransport(logger Logger) Transport {
+	return func(req *http.Request, next Handler) (*http.Response, error) {
+		resp, err := next(req)
+		if err != nil {
+			logger.Printf("%s %s: %v", req.Method, req.URL, err)
+			return resp, err
+		}
+		logger.Printf("%s %s: %d", req.Method, req.URL, resp.StatusCode)
+		return resp, nil
+	}
+}
+
+// ClogTransport logs every outbound request and its outcome through the
+// zonesan/clog structured logger used throughout the rest of DataFoundry's
+// HTTP handlers, such as the market.Market handler.
+func ClogTransport() Transport {
+	return func(req *http.Request, next Handler) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next(req)
+		if err != nil {
+			clog.Error("payment agent", req.Method, req.URL.RequestURI(), time.Since(start), err)
+			return resp, err
+		}
+		clog.Info("payment agent", req.Method, req.URL.RequestURI(), resp.StatusCode, time.Since(start))
+		return resp, nil
+	}
+}
+
+var (
+	requestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "datafoundry_payment",
+		Subsystem: "agent",
+		Name:      "requests_in_flight",
+		Help:      "Number of payment components API requests currently in flight.",
+	})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "datafoundry_payment",
+		Subsystem: "agent",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of payment components API requests.",
+	}, []string{"method"})
+	requestErrorsByCode = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datafoundry_payment",
+		Subsystem: "agent",
+		Name:      "request_errors_total",
+		Help:      "Payment components API errors, labelled by ErrCode*.",
+	}, []string{"code"})
+)
+
+func init() {
+	prometheus.MustRegister(requestsInFlight, requestDuration, requestErrorsByCode)
+}
+
+// MetricsTransport records request latency and in-flight request count via
+// Prometheus. Errors are counted separately by recordAPIError, once Do has
+// decoded the response body into an APIError.
+func MetricsTransport() Transport {
+	return func(req *http.Request, next Handler) (*http.Response, error) {
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+
+		start := time.Now()
+		resp, err := next(req)
+		requestDuration.WithLabelValues(req.Method).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// recordAPIError increments the ErrCode*-labelled error counter for a
+// non-nil error returned by CheckResponse.
+func recordAPIError(err error) {
+	if err == nil {
+		return
+	}
+	code := ErrCodeUnknownError
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.Code
+	}
+	requestErrorsByCode.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// TraceTransport injects the active OpenTelemetry span context from the
+// request's context into its outbound headers, so payment/recharge/checkout
+// calls can be correlated end-to-end across DataFoundry services.
+func TraceTransport() Transport {
+	return func(req *http.Request, next Handler) (*http.Response, error) {
+		otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+		return next(req)
+	}
+}
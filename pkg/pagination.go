@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+)
+
+// ListOptions specifies the optional parameters to various List methods that
+// support pagination.
+type ListOptions struct {
+	// Page of results to retrieve.
+	Page int `url:"page,omitempty"`
+
+	// Number of results to include per page.
+	PerPage int `url:"per_page,omitempty"`
+}
+
+// Response wraps the standard http.Response returned by the payment
+// components API and adds convenience fields for pagination, parsed from
+// the Link header of a List* call.
+type Response struct {
+	*http.Response
+
+	NextPage  int
+	PrevPage  int
+	FirstPage int
+	LastPage  int
+}
+
+// newResponse wraps r and populates the pagination fields from its Link
+// header, if present.
+func newResponse(r *http.Response) *Response {
+	resp := &Response{Response: r}
+	resp.populatePageValues()
+	return resp
+}
+
+// populatePageValues parses the Link header, in the standard format
+// described at https://tools.ietf.org/html/rfc5988#section-5, and fills in
+// the NextPage, PrevPage, FirstPage, and LastPage fields.
+func (r *Response) populatePageValues() {
+	if link := r.Header.Get("Link"); link != "" {
+		for _, segment := range strings.Split(link, ",") {
+			parts := strings.Split(strings.TrimSpace(segment), ";")
+			if len(parts) < 2 {
+				continue
+			}
+
+			urlPart := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(urlPart, "<") || !strings.HasSuffix(urlPart, ">") {
+				continue
+			}
+			rawURL := urlPart[1 : len(urlPart)-1]
+
+			u, err := url.Parse(rawURL)
+			if err != nil {
+				continue
+			}
+			page, err := strconv.Atoi(u.Query().Get("page"))
+			if err != nil {
+				continue
+			}
+
+			for _, rel := range parts[1:] {
+				rel = strings.TrimSpace(rel)
+				switch rel {
+				case `rel="next"`:
+					r.NextPage = page
+				case `rel="prev"`:
+					r.PrevPage = page
+				case `rel="first"`:
+					r.FirstPage = page
+				case `rel="last"`:
+					r.LastPage = page
+				}
+			}
+		}
+	}
+}
+
+// addOptions adds the parameters in opts as URL query parameters to s. opts
+// must be a struct whose fields may contain "url" tags.
+func addOptions(s string, opts interface{}) (string, error) {
+	v := reflect.ValueOf(opts)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return s, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return s, err
+	}
+
+	qs, err := query.Values(opts)
+	if err != nil {
+		return s, err
+	}
+
+	u.RawQuery = qs.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPopulatePageValues(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want Response
+	}{
+		{
+			name: "no link header",
+			link: "",
+			want: Response{},
+		},
+		{
+			name: "first and next",
+			link: `<https://example.com/market?page=2>; rel="next", <https://example.com/market?page=1>; rel="first"`,
+			want: Response{NextPage: 2, FirstPage: 1},
+		},
+		{
+			name: "all four",
+			link: `<https://example.com/market?page=2>; rel="next", <https://example.com/market?page=1>; rel="prev", <https://example.com/market?page=1>; rel="first", <https://example.com/market?page=5>; rel="last"`,
+			want: Response{NextPage: 2, PrevPage: 1, FirstPage: 1, LastPage: 5},
+		},
+		{
+			name: "malformed segment is ignored",
+			link: `not-a-valid-link-segment, <https://example.com/market?page=3>; rel="next"`,
+			want: Response{NextPage: 3},
+		},
+		{
+			name: "non-numeric page is ignored",
+			link: `<https://example.com/market?page=abc>; rel="next"`,
+			want: Response{},
+		},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.link != "" {
+			header.Set("Link", tt.link)
+		}
+		resp := &Response{Response: &http.Response{Header: header}}
+		resp.populatePageValues()
+
+		if resp.NextPage != tt.want.NextPage || resp.PrevPage != tt.want.PrevPage ||
+			resp.FirstPage != tt.want.FirstPage || resp.LastPage != tt.want.LastPage {
+			t.Errorf("%s: populatePageValues() = %+v, want %+v", tt.name, *resp, tt.want)
+		}
+	}
+}
+
+func TestAddOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts interface{}
+		want string
+	}{
+		{"nil opts", (*ListOptions)(nil), "market"},
+		{"zero value opts", &ListOptions{}, "market"},
+		{"page only", &ListOptions{Page: 2}, "market?page=2"},
+		{"page and per_page", &ListOptions{Page: 2, PerPage: 50}, "market?page=2&per_page=50"},
+	}
+
+	for _, tt := range tests {
+		got, err := addOptions("market", tt.opts)
+		if err != nil {
+			t.Errorf("%s: addOptions() error = %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: addOptions() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
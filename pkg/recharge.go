@@ -0,0 +1,50 @@
+package pkg
+
+import "context"
+
+// RechargeAgent handles communication with the recharge endpoints of the
+// payment components API.
+type RechargeAgent service
+
+// RechargeRequest is the payload for submitting a new recharge.
+type RechargeRequest struct {
+	AccountID string  `json:"account_id"`
+	Amount    float64 `json:"amount"`
+}
+
+// Recharge represents a single recharge transaction.
+type Recharge struct {
+	ID        string  `json:"id"`
+	AccountID string  `json:"account_id"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"`
+}
+
+// Create submits a new recharge request.
+func (a *RechargeAgent) Create(ctx context.Context, in *RechargeRequest) (*Recharge, error) {
+	req, err := a.agent.NewRequestWithContext(ctx, "POST", "recharge", in)
+	if err != nil {
+		return nil, err
+	}
+
+	var r Recharge
+	_, err = a.agent.Do(ctx, req, &r)
+	return &r, err
+}
+
+// List retrieves recharge history, honoring pagination via opts.
+func (a *RechargeAgent) List(ctx context.Context, opts *ListOptions) ([]*Recharge, *Response, error) {
+	u, err := addOptions("recharge", opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := a.agent.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var list []*Recharge
+	resp, err := a.agent.Do(ctx, req, &list)
+	return list, resp, err
+}